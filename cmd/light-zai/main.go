@@ -18,8 +18,14 @@ func main() {
 	}
 
 	ctx := context.Background()
-	if len(os.Args) > 1 {
-		q := strings.TrimSpace(strings.Join(os.Args[1:], " "))
+	args := os.Args[1:]
+	newSession := false
+	if len(args) > 0 && args[0] == "--new" {
+		newSession = true
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		q := strings.TrimSpace(strings.Join(args, " "))
 		ans, err := cli.Chat(ctx, []lightzai.Message{
 			{Role: "system", Content: cli.SystemPrompt()},
 			{Role: "user", Content: q},
@@ -32,7 +38,7 @@ func main() {
 		return
 	}
 
-	if err := lightzai.RunREPL(ctx, cli); err != nil {
+	if err := lightzai.RunREPL(ctx, cli, newSession); err != nil {
 		fmt.Fprintln(os.Stderr, "REPL 오류:", err)
 		os.Exit(1)
 	}
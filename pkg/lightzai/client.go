@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,19 +19,25 @@ import (
 
 	"syscall"
 	"unsafe"
+
+	"github.com/wwwhana/light-zai/pkg/lightzai/secrets"
 )
 
 type Config struct {
-	APIKey       string
-	Model        string
-	BaseURL      string
-	APIPrefix    string
-	MaxTokens    int
-	Temperature  float64
-	Timeout      time.Duration
-	MaxHistory   int
-	ScreenWidth  int
-	ScreenHeight int
+	Profile       string
+	APIKey        string
+	Model         string
+	BaseURL       string
+	APIPrefix     string
+	Scheme        string
+	MaxTokens     int
+	Temperature   float64
+	Timeout       time.Duration
+	MaxHistory    int
+	ScreenWidth   int
+	ScreenHeight  int
+	RedactMode    secrets.Mode
+	HistoryPolicy string
 }
 
 type Message struct {
@@ -40,66 +48,118 @@ type Message struct {
 type Client struct {
 	cfg        Config
 	httpClient *http.Client
+	policy     HistoryPolicy
 }
 
-type savedConfig struct {
+// profileConfig is one named endpoint in config.json's "profiles" map.
+type profileConfig struct {
 	APIKey      string  `json:"apiKey"`
 	Model       string  `json:"model"`
 	BaseURL     string  `json:"baseUrl"`
 	APIPrefix   string  `json:"apiPrefix"`
+	Scheme      string  `json:"scheme"`
 	MaxTokens   int     `json:"maxTokens"`
 	Temperature float64 `json:"temperature"`
 }
 
-func loadSavedConfig() savedConfig {
+// fileConfig is the on-disk shape of ~/.config/light-zai/config.json.
+type fileConfig struct {
+	Default  string                   `json:"default"`
+	Profiles map[string]profileConfig `json:"profiles"`
+}
+
+const defaultProfileName = "zai"
+
+func configPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil || home == "" {
-		return savedConfig{}
+		return "", fmt.Errorf("home directory를 찾을 수 없습니다")
+	}
+	return filepath.Join(home, ".config", "light-zai", "config.json"), nil
+}
+
+// loadFileConfig reads config.json and transparently migrates the legacy
+// flat single-endpoint shape ({"apiKey": ..., "model": ...}) into a
+// "default"/"profiles" map under defaultProfileName.
+func loadFileConfig() fileConfig {
+	p, err := configPath()
+	if err != nil {
+		return fileConfig{}
 	}
-	p := filepath.Join(home, ".config", "light-zai", "config.json")
 	b, err := os.ReadFile(p)
 	if err != nil {
-		return savedConfig{}
+		return fileConfig{}
 	}
-	var cfg savedConfig
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return savedConfig{}
+	var fc fileConfig
+	if err := json.Unmarshal(b, &fc); err == nil && len(fc.Profiles) > 0 {
+		return fc
+	}
+	var legacy profileConfig
+	if err := json.Unmarshal(b, &legacy); err != nil || legacy == (profileConfig{}) {
+		return fileConfig{}
+	}
+	return fileConfig{
+		Default:  defaultProfileName,
+		Profiles: map[string]profileConfig{defaultProfileName: legacy},
 	}
-	return cfg
 }
 
-func saveUserConfig(cfg savedConfig) error {
-	home, err := os.UserHomeDir()
-	if err != nil || home == "" {
-		return fmt.Errorf("home directory를 찾을 수 없습니다")
+// activeProfileName resolves which profile to use: LZAI_PROFILE env wins,
+// then the file's "default", then defaultProfileName.
+func activeProfileName(fc fileConfig) string {
+	name := strings.TrimSpace(os.Getenv("LZAI_PROFILE"))
+	if name == "" {
+		name = fc.Default
 	}
-	dir := filepath.Join(home, ".config", "light-zai")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	if name == "" {
+		name = defaultProfileName
+	}
+	return name
+}
+
+// saveProfileConfig merges pc into the named profile on disk, creating the
+// profiles map (and a "default") if config.json doesn't exist yet.
+func saveProfileConfig(name string, pc profileConfig) error {
+	p, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
 		return err
 	}
-	p := filepath.Join(dir, "config.json")
 
-	merged := loadSavedConfig()
-	if cfg.APIKey != "" {
-		merged.APIKey = cfg.APIKey
+	fc := loadFileConfig()
+	if fc.Profiles == nil {
+		fc.Profiles = map[string]profileConfig{}
+	}
+	merged := fc.Profiles[name]
+	if pc.APIKey != "" {
+		merged.APIKey = pc.APIKey
 	}
-	if cfg.Model != "" {
-		merged.Model = cfg.Model
+	if pc.Model != "" {
+		merged.Model = pc.Model
 	}
-	if cfg.BaseURL != "" {
-		merged.BaseURL = cfg.BaseURL
+	if pc.BaseURL != "" {
+		merged.BaseURL = pc.BaseURL
 	}
-	if cfg.APIPrefix != "" {
-		merged.APIPrefix = cfg.APIPrefix
+	if pc.APIPrefix != "" {
+		merged.APIPrefix = pc.APIPrefix
 	}
-	if cfg.MaxTokens > 0 {
-		merged.MaxTokens = cfg.MaxTokens
+	if pc.Scheme != "" {
+		merged.Scheme = pc.Scheme
 	}
-	if cfg.Temperature > 0 {
-		merged.Temperature = cfg.Temperature
+	if pc.MaxTokens > 0 {
+		merged.MaxTokens = pc.MaxTokens
+	}
+	if pc.Temperature > 0 {
+		merged.Temperature = pc.Temperature
+	}
+	fc.Profiles[name] = merged
+	if fc.Default == "" {
+		fc.Default = name
 	}
 
-	b, err := json.MarshalIndent(merged, "", "  ")
+	b, err := json.MarshalIndent(fc, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -178,7 +238,9 @@ func dynamicMaxHistory(totalMemMB int) int {
 }
 
 func DefaultConfigFromEnv() Config {
-	saved := loadSavedConfig()
+	fc := loadFileConfig()
+	profile := activeProfileName(fc)
+	saved := fc.Profiles[profile]
 	apiKey := os.Getenv("ZAI_API_KEY")
 	if apiKey == "" {
 		apiKey = os.Getenv("LZAI_API_KEY")
@@ -207,6 +269,13 @@ func DefaultConfigFromEnv() Config {
 	if prefix == "" {
 		prefix = "/api/paas/v4"
 	}
+	scheme := os.Getenv("LZAI_SCHEME")
+	if scheme == "" {
+		scheme = saved.Scheme
+	}
+	if scheme == "" {
+		scheme = "https"
+	}
 
 	totalMemMB := detectTotalMemoryMB()
 	defaultTokens := dynamicTokenLimit(totalMemMB)
@@ -244,17 +313,26 @@ func DefaultConfigFromEnv() Config {
 	if screenH < 8 {
 		screenH = 8
 	}
+	redactMode, _ := secrets.ParseMode(os.Getenv("LZAI_REDACT_MODE"))
+	historyPolicy := strings.ToLower(strings.TrimSpace(os.Getenv("LZAI_HISTORY_POLICY")))
+	if historyPolicy != "summarize" {
+		historyPolicy = "window"
+	}
 	return Config{
-		APIKey:       apiKey,
-		Model:        model,
-		BaseURL:      base,
-		APIPrefix:    prefix,
-		MaxTokens:    maxTokens,
-		Temperature:  temp,
-		Timeout:      time.Duration(timeoutSec) * time.Second,
-		MaxHistory:   maxHistory,
-		ScreenWidth:  screenW,
-		ScreenHeight: screenH,
+		Profile:       profile,
+		APIKey:        apiKey,
+		Model:         model,
+		BaseURL:       base,
+		APIPrefix:     prefix,
+		Scheme:        scheme,
+		MaxTokens:     maxTokens,
+		Temperature:   temp,
+		Timeout:       time.Duration(timeoutSec) * time.Second,
+		MaxHistory:    maxHistory,
+		ScreenWidth:   screenW,
+		ScreenHeight:  screenH,
+		RedactMode:    redactMode,
+		HistoryPolicy: historyPolicy,
 	}
 }
 
@@ -271,12 +349,154 @@ func NewClient(cfg Config) (*Client, error) {
 	if cfg.ScreenHeight < 8 {
 		cfg.ScreenHeight = 8
 	}
+	if cfg.Scheme == "" {
+		cfg.Scheme = "https"
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = defaultProfileName
+	}
+	if cfg.HistoryPolicy == "" {
+		cfg.HistoryPolicy = "window"
+	}
 	return &Client{
 		cfg:        cfg,
 		httpClient: &http.Client{Timeout: cfg.Timeout},
+		policy:     policyFromName(cfg.HistoryPolicy),
 	}, nil
 }
 
+// Profile returns the name of the profile the client is currently using.
+func (c *Client) Profile() string {
+	return c.cfg.Profile
+}
+
+// ProfileNames lists every profile defined in config.json, sorted.
+func (c *Client) ProfileNames() []string {
+	fc := loadFileConfig()
+	names := make([]string, 0, len(fc.Profiles))
+	for name := range fc.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchProfile reloads config.json and points the client at the named
+// profile without restarting. Fields left blank in that profile keep their
+// current value, matching saveProfileConfig's merge behavior.
+func (c *Client) SwitchProfile(name string) error {
+	fc := loadFileConfig()
+	pc, ok := fc.Profiles[name]
+	if !ok {
+		return fmt.Errorf("프로필 %q을(를) 찾을 수 없습니다. /profiles 로 확인하세요", name)
+	}
+	c.applyProfile(name, pc)
+	return nil
+}
+
+func (c *Client) applyProfile(name string, pc profileConfig) {
+	if pc.APIKey != "" {
+		c.cfg.APIKey = pc.APIKey
+	}
+	if pc.Model != "" {
+		c.cfg.Model = pc.Model
+	}
+	if pc.BaseURL != "" {
+		c.cfg.BaseURL = pc.BaseURL
+	}
+	if pc.APIPrefix != "" {
+		c.cfg.APIPrefix = pc.APIPrefix
+	}
+	if pc.Scheme != "" {
+		c.cfg.Scheme = pc.Scheme
+	}
+	if pc.MaxTokens > 0 {
+		c.cfg.MaxTokens = pc.MaxTokens
+	}
+	if pc.Temperature > 0 {
+		c.cfg.Temperature = pc.Temperature
+	}
+	c.cfg.Profile = name
+}
+
+// RedactMode returns the secret-scanning mode applied to outgoing user
+// messages by Chat and ChatStream.
+func (c *Client) RedactMode() secrets.Mode {
+	return c.cfg.RedactMode
+}
+
+// SetRedactMode changes the secret-scanning mode at runtime.
+func (c *Client) SetRedactMode(m secrets.Mode) {
+	c.cfg.RedactMode = m
+}
+
+// redactMessages scans the most recently added user message (the one
+// about to be sent) and, in ModeWarn/ModeBlock, prints a "redacted>"
+// summary line. In ModeBlock the matched text is replaced in a copy of
+// messages; ModeWarn reports but leaves messages untouched.
+func (c *Client) redactMessages(messages []Message) []Message {
+	if c.cfg.RedactMode == secrets.ModeOff || len(messages) == 0 {
+		return messages
+	}
+	last := len(messages) - 1
+	if messages[last].Role != "user" {
+		return messages
+	}
+	res := secrets.Scan(messages[last].Content, c.cfg.RedactMode)
+	if len(res.Matches) == 0 {
+		return messages
+	}
+	fmt.Println(secrets.Summary(res.Matches))
+	if !res.Blocked {
+		return messages
+	}
+	out := make([]Message, len(messages))
+	copy(out, messages)
+	out[last].Content = res.Text
+	return out
+}
+
+// HistoryPolicyName returns the active history policy: "window" or
+// "summarize".
+func (c *Client) HistoryPolicyName() string {
+	if c.cfg.HistoryPolicy == "" {
+		return "window"
+	}
+	return c.cfg.HistoryPolicy
+}
+
+// SetHistoryPolicyName switches the active history policy at runtime.
+// Returns false (leaving the policy unchanged) for an unrecognized name.
+func (c *Client) SetHistoryPolicyName(name string) bool {
+	switch name {
+	case "window":
+		c.cfg.HistoryPolicy = name
+		c.policy = WindowPolicy{}
+	case "summarize":
+		c.cfg.HistoryPolicy = name
+		c.policy = SummarizePolicy{}
+	default:
+		return false
+	}
+	return true
+}
+
+// historyBudget is the token ceiling WindowPolicy/SummarizePolicy trim the
+// sent history down to: 60% of MaxTokens, leaving headroom for the answer.
+func (c *Client) historyBudget() int {
+	budget := int(float64(c.cfg.MaxTokens) * 0.6)
+	if budget < 1 {
+		budget = 1
+	}
+	return budget
+}
+
+// ApplyHistoryPolicy trims messages down to the client's token budget using
+// its active HistoryPolicy.
+func (c *Client) ApplyHistoryPolicy(ctx context.Context, messages []Message) ([]Message, error) {
+	return c.policy.Apply(ctx, c, messages, c.historyBudget())
+}
+
 type chatRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
@@ -294,11 +514,20 @@ type chatResponse struct {
 	} `json:"error,omitempty"`
 }
 
+func (c *Client) endpointURL() string {
+	scheme := c.cfg.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/chat/completions", scheme, c.cfg.BaseURL, c.cfg.APIPrefix)
+}
+
 func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
 	if strings.TrimSpace(c.cfg.APIKey) == "" {
 		return "", fmt.Errorf("API 키가 비어 있습니다. /setup 명령으로 키를 먼저 설정하세요")
 	}
-	url := fmt.Sprintf("https://%s%s/chat/completions", c.cfg.BaseURL, c.cfg.APIPrefix)
+	messages = c.redactMessages(messages)
+	url := c.endpointURL()
 	payload := chatRequest{
 		Model:       c.cfg.Model,
 		Messages:    messages,
@@ -337,6 +566,126 @@ func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
 	return out.Choices[0].Message.Content, nil
 }
 
+// StreamChunk is one incremental delta emitted by ChatStream. A chunk with
+// Err != nil ends the stream; a chunk with Done == true marks a clean end
+// (with or without trailing Content).
+type StreamChunk struct {
+	Content string
+	Done    bool
+	Err     error
+}
+
+type streamFrame struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream POSTs with Stream: true and emits per-token deltas on the
+// returned channel as the OpenAI-compatible SSE frames arrive. If the
+// server ignores streaming and answers with a normal JSON body instead of
+// text/event-stream, the full answer is delivered as a single chunk.
+// The channel is always closed when the request finishes, errors, or ctx
+// is cancelled.
+func (c *Client) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	if strings.TrimSpace(c.cfg.APIKey) == "" {
+		return nil, fmt.Errorf("API 키가 비어 있습니다. /setup 명령으로 키를 먼저 설정하세요")
+	}
+	messages = c.redactMessages(messages)
+	payload := chatRequest{
+		Model:       c.cfg.Model,
+		Messages:    messages,
+		MaxTokens:   c.cfg.MaxTokens,
+		Temperature: c.cfg.Temperature,
+		Stream:      true,
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpointURL(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		go func() {
+			defer resp.Body.Close()
+			defer close(ch)
+			var out chatResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				ch <- StreamChunk{Err: err}
+				return
+			}
+			if out.Error != nil {
+				ch <- StreamChunk{Err: errors.New(out.Error.Message)}
+				return
+			}
+			if len(out.Choices) == 0 {
+				ch <- StreamChunk{Err: errors.New("empty response")}
+				return
+			}
+			ch <- StreamChunk{Content: out.Choices[0].Message.Content, Done: true}
+		}()
+		return ch, nil
+	}
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(ch)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "" {
+				continue
+			}
+			if data == "[DONE]" {
+				ch <- StreamChunk{Done: true}
+				return
+			}
+			var frame streamFrame
+			if err := json.Unmarshal([]byte(data), &frame); err != nil {
+				continue
+			}
+			if frame.Error != nil {
+				ch <- StreamChunk{Err: errors.New(frame.Error.Message)}
+				return
+			}
+			for _, choice := range frame.Choices {
+				if choice.Delta.Content != "" {
+					ch <- StreamChunk{Content: choice.Delta.Content}
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			ch <- StreamChunk{Err: err}
+			return
+		}
+		ch <- StreamChunk{Done: true}
+	}()
+	return ch, nil
+}
+
 func runeDisplayWidth(r rune) int {
 	if r == '	' {
 		return 4
@@ -387,26 +736,98 @@ func wrapText(s string, width int) []string {
 	return out
 }
 
-func pagePrint(text string, width, height int, in *bufio.Scanner) {
-	lines := wrapText(text, width)
+// streamPrint renders chunks as they arrive, word-wrapping only the lines
+// that are already complete (everything up to the last received "\n") and
+// pausing with --More-- every pageSize rendered lines.
+// If the user answers "q" at a pause, cancel is invoked so the in-flight
+// request is aborted, and the channel is drained in the background so the
+// ChatStream goroutine doesn't leak. It returns the accumulated answer
+// text (for history) and the first stream error, if any.
+func streamPrint(cancel context.CancelFunc, chunks <-chan StreamChunk, width, height int, in *bufio.Scanner) (string, error) {
 	pageSize := height - 2
 	if pageSize < 3 {
 		pageSize = 3
 	}
-	for i := 0; i < len(lines); i++ {
-		fmt.Println(lines[i])
-		if (i+1)%pageSize == 0 && i+1 < len(lines) {
-			fmt.Print("--More-- (Enter 계속, q 중단): ")
-			if !in.Scan() {
-				fmt.Println()
-				return
+
+	var full strings.Builder
+	pending := ""
+	lineCount := 0
+	cancelled := false
+
+	printLine := func(line string) bool {
+		fmt.Println(line)
+		lineCount++
+		if lineCount%pageSize != 0 {
+			return true
+		}
+		fmt.Print("--More-- (Enter 계속, q 중단): ")
+		if !in.Scan() {
+			fmt.Println()
+			return false
+		}
+		ans := strings.TrimSpace(strings.ToLower(in.Text()))
+		if ans == "q" || ans == "quit" {
+			cancelled = true
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	var streamErr error
+loop:
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			if !errors.Is(chunk.Err, context.Canceled) {
+				streamErr = chunk.Err
+			}
+			break loop
+		}
+		full.WriteString(chunk.Content)
+		pending += chunk.Content
+		for {
+			idx := strings.IndexByte(pending, '\n')
+			if idx < 0 {
+				break
 			}
-			ans := strings.TrimSpace(strings.ToLower(in.Text()))
-			if ans == "q" || ans == "quit" {
+			for _, l := range wrapText(pending[:idx], width) {
+				if !printLine(l) {
+					break loop
+				}
+			}
+			pending = pending[idx+1:]
+		}
+		if chunk.Done {
+			break loop
+		}
+	}
+
+	if !cancelled && pending != "" {
+		for _, l := range wrapText(pending, width) {
+			if !printLine(l) {
 				break
 			}
 		}
 	}
+	if cancelled {
+		go func() {
+			for range chunks {
+			}
+		}()
+	}
+	return full.String(), streamErr
+}
+
+func printChatError(err error) {
+	errMsg := err.Error()
+	lower := strings.ToLower(errMsg)
+	if strings.Contains(lower, "insufficient balance") || strings.Contains(lower, "no resource package") {
+		fmt.Println("error> 크레딧/리소스 패키지가 부족합니다.")
+		fmt.Println("hint> ZAI 콘솔에서 잔액/패키지를 충전한 뒤 다시 시도하세요.")
+		fmt.Println("hint> 키/엔드포인트 점검: ZAI_API_KEY, LZAI_BASE_URL, LZAI_API_PREFIX")
+	} else {
+		fmt.Println("error>", errMsg)
+	}
 }
 
 func detectTTYSize(defaultW, defaultH int) (int, int) {
@@ -436,9 +857,17 @@ func detectTTYSize(defaultW, defaultH int) (int, int) {
 	return w, h
 }
 
-func runSetupFlow(s *bufio.Scanner, c *Client) {
-	fmt.Println("[온보딩] Z.AI 설정을 시작합니다. Enter를 누르면 기본값을 사용합니다.")
-	fmt.Print("ZAI API Key 입력: ")
+// runSetupFlow walks the user through onboarding for the named profile
+// (falling back to the client's current profile, or defaultProfileName).
+func runSetupFlow(s *bufio.Scanner, c *Client, profile string) {
+	if profile == "" {
+		profile = c.cfg.Profile
+	}
+	if profile == "" {
+		profile = defaultProfileName
+	}
+	fmt.Printf("[온보딩] %q 프로필 설정을 시작합니다. Enter를 누르면 기본값을 사용합니다.\n", profile)
+	fmt.Print("API Key 입력: ")
 	if !s.Scan() {
 		fmt.Println("온보딩이 취소되었습니다.")
 		return
@@ -476,22 +905,32 @@ func runSetupFlow(s *bufio.Scanner, c *Client) {
 		apiPrefix = c.cfg.APIPrefix
 	}
 
-	c.cfg.APIKey = key
-	c.cfg.Model = model
-	c.cfg.BaseURL = baseURL
-	c.cfg.APIPrefix = apiPrefix
+	defaultScheme := c.cfg.Scheme
+	if defaultScheme == "" {
+		defaultScheme = "https"
+	}
+	fmt.Printf("Scheme (http/https, 로컬 Ollama/vLLM은 http) [%s]: ", defaultScheme)
+	if !s.Scan() {
+		return
+	}
+	scheme := strings.TrimSpace(s.Text())
+	if scheme == "" {
+		scheme = defaultScheme
+	}
 
-	err := saveUserConfig(savedConfig{
+	pc := profileConfig{
 		APIKey:    key,
 		Model:     model,
 		BaseURL:   baseURL,
 		APIPrefix: apiPrefix,
-	})
-	if err != nil {
+		Scheme:    scheme,
+	}
+	if err := saveProfileConfig(profile, pc); err != nil {
 		fmt.Println("설정 저장 실패:", err)
 		return
 	}
-	fmt.Println("온보딩 완료: 설정이 ~/.config/light-zai/config.json 에 저장되었습니다.")
+	c.applyProfile(profile, pc)
+	fmt.Printf("온보딩 완료: %q 프로필 설정이 ~/.config/light-zai/config.json 에 저장되었습니다.\n", profile)
 }
 
 func trimHistory(history []Message, max int) []Message {
@@ -503,15 +942,59 @@ func trimHistory(history []Message, max int) []Message {
 	return append([]Message{sys}, tail...)
 }
 
-func RunREPL(ctx context.Context, c *Client) error {
-	fmt.Println("Light-zai Go (ARMv7/저메모리) — 종료: /exit, 초기화: /clear, 설정: /setup")
+func RunREPL(ctx context.Context, c *Client, newSession bool) error {
+	fmt.Println("Light-zai Go (ARMv7/저메모리) — 종료: /exit, 초기화: /clear, 설정: /setup, 프로필: /profiles, /use, 민감정보: /redact, 기록 정책: /policy, /tokens")
+	fmt.Println("세션: /save, /load, /list, /rename, /branch, /export")
 	s := bufio.NewScanner(os.Stdin)
 	s.Buffer(make([]byte, 0, 4096), 1024*1024)
 	if strings.TrimSpace(c.cfg.APIKey) == "" {
 		fmt.Println("API 키가 없어 온보딩을 시작합니다. 필요 시 /setup 명령으로 다시 실행할 수 있습니다.")
-		runSetupFlow(s, c)
+		runSetupFlow(s, c, c.cfg.Profile)
+	}
+
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+
+	var sess *Session
+	var lock *sessionLock
+	if !newSession {
+		if hdr, herr := mostRecentSessionHeader(dir); herr == nil && hdr != nil {
+			if l, lerr := lockSession(dir, hdr.ID); lerr == nil {
+				msgs, merr := loadSessionMessages(dir, hdr.ID)
+				if merr == nil {
+					sess = &Session{SessionHeader: *hdr, Messages: msgs}
+					lock = l
+					fmt.Printf("세션 %q(%s) 을(를) 이어서 시작합니다. (%d개 메시지)\n", sess.displayName(), sess.ID, len(sess.Messages))
+				} else {
+					l.unlock()
+				}
+			} else {
+				fmt.Println("최근 세션이 다른 프로세스에서 사용 중이어서 새 세션을 시작합니다.")
+			}
+		}
+	}
+	if sess == nil {
+		sess = newSessionFor(c)
+		l, lerr := lockSession(dir, sess.ID)
+		if lerr != nil {
+			return lerr
+		}
+		lock = l
+		if err := saveSessionHeader(dir, sess.SessionHeader); err != nil {
+			return err
+		}
 	}
-	history := []Message{{Role: "system", Content: "당신은 간결하고 정확한 코딩 도우미입니다."}}
+	defer func() {
+		if lock != nil {
+			lock.unlock()
+		}
+	}()
+	if err := ensureSystemPrompt(dir, sess); err != nil {
+		fmt.Println("세션 기록 실패:", err)
+	}
+
 	for {
 		fmt.Print("you> ")
 		if !s.Scan() {
@@ -525,35 +1008,258 @@ func RunREPL(ctx context.Context, c *Client) error {
 			return nil
 		}
 		if text == "/clear" {
-			history = history[:1]
-			fmt.Println("대화 기록을 초기화했습니다.")
+			lock.unlock()
+			sess = newSessionFor(c)
+			l, lerr := lockSession(dir, sess.ID)
+			if lerr != nil {
+				return lerr
+			}
+			lock = l
+			if err := saveSessionHeader(dir, sess.SessionHeader); err != nil {
+				fmt.Println("세션 저장 실패:", err)
+			}
+			if err := ensureSystemPrompt(dir, sess); err != nil {
+				fmt.Println("세션 기록 실패:", err)
+			}
+			fmt.Println("대화 기록을 초기화했습니다. 새 세션:", sess.ID)
+			continue
+		}
+		if text == "/save" || strings.HasPrefix(text, "/save ") {
+			name := strings.TrimSpace(strings.TrimPrefix(text, "/save"))
+			if name != "" {
+				sess.Name = name
+			}
+			if err := saveSessionHeader(dir, sess.SessionHeader); err != nil {
+				fmt.Println("세션 저장 실패:", err)
+			} else {
+				fmt.Printf("세션을 저장했습니다: %s (%s)\n", sess.displayName(), sess.ID)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "/load ") {
+			target := strings.TrimSpace(strings.TrimPrefix(text, "/load "))
+			if target == "" {
+				fmt.Println("사용법: /load <이름|ID>")
+				continue
+			}
+			hdr, err := findSessionHeader(dir, target)
+			if err != nil {
+				fmt.Println("error>", err)
+				continue
+			}
+			msgs, err := loadSessionMessages(dir, hdr.ID)
+			if err != nil {
+				fmt.Println("세션 불러오기 실패:", err)
+				continue
+			}
+			// Reloading the session we're already in would flock a second
+			// fd against the one `lock` already holds and spuriously fail
+			// (flock is per-fd, not per-process) — just skip the relock.
+			if hdr.ID != sess.ID {
+				newLock, err := lockSession(dir, hdr.ID)
+				if err != nil {
+					fmt.Println("error>", err)
+					continue
+				}
+				lock.unlock()
+				lock = newLock
+			}
+			sess = &Session{SessionHeader: *hdr, Messages: msgs}
+			fmt.Printf("세션 %q(%s) 을(를) 불러왔습니다. (%d개 메시지)\n", sess.displayName(), sess.ID, len(sess.Messages))
+			continue
+		}
+		if text == "/list" {
+			headers, err := listSessionHeaders(dir)
+			if err != nil {
+				fmt.Println("세션 목록 조회 실패:", err)
+				continue
+			}
+			if len(headers) == 0 {
+				fmt.Println("저장된 세션이 없습니다.")
+			}
+			for _, h := range headers {
+				marker := "  "
+				if h.ID == sess.ID {
+					marker = "* "
+				}
+				fmt.Printf("%s%s\t%s\t%s\n", marker, h.ID, h.displayName(), h.UpdatedAt.Format("2006-01-02 15:04"))
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "/rename ") {
+			name := strings.TrimSpace(strings.TrimPrefix(text, "/rename "))
+			if name == "" {
+				fmt.Println("사용법: /rename <새 이름>")
+				continue
+			}
+			sess.Name = name
+			if err := saveSessionHeader(dir, sess.SessionHeader); err != nil {
+				fmt.Println("세션 이름 변경 실패:", err)
+			} else {
+				fmt.Printf("세션 이름을 %q(으)로 변경했습니다.\n", name)
+			}
+			continue
+		}
+		if text == "/branch" || strings.HasPrefix(text, "/branch ") {
+			n := 0
+			if arg := strings.TrimSpace(strings.TrimPrefix(text, "/branch")); arg != "" {
+				v, err := strconv.Atoi(arg)
+				if err != nil || v < 0 {
+					fmt.Println("사용법: /branch [n]")
+					continue
+				}
+				n = v
+			}
+			branch, err := branchSession(dir, sess, n)
+			if err != nil {
+				fmt.Println("세션 분기 실패:", err)
+				continue
+			}
+			newLock, err := lockSession(dir, branch.ID)
+			if err != nil {
+				fmt.Println("error>", err)
+				continue
+			}
+			lock.unlock()
+			lock = newLock
+			sess = branch
+			if err := ensureSystemPrompt(dir, sess); err != nil {
+				fmt.Println("세션 기록 실패:", err)
+			}
+			fmt.Printf("세션을 %s 에서 분기했습니다. (%d개 메시지 유지)\n", sess.ID, len(sess.Messages))
+			continue
+		}
+		if strings.HasPrefix(text, "/export ") {
+			path := strings.TrimSpace(strings.TrimPrefix(text, "/export "))
+			if path == "" {
+				fmt.Println("사용법: /export <path>.md")
+				continue
+			}
+			if err := exportMarkdown(sess, path); err != nil {
+				fmt.Println("내보내기 실패:", err)
+			} else {
+				fmt.Println("내보내기 완료:", path)
+			}
 			continue
 		}
 		if text == "/setup" {
-			runSetupFlow(s, c)
+			runSetupFlow(s, c, c.cfg.Profile)
+			continue
+		}
+		if strings.HasPrefix(text, "/setup ") {
+			runSetupFlow(s, c, strings.TrimSpace(strings.TrimPrefix(text, "/setup ")))
+			continue
+		}
+		if text == "/profiles" {
+			names := c.ProfileNames()
+			if len(names) == 0 {
+				fmt.Println("등록된 프로필이 없습니다. /setup <이름> 으로 추가하세요.")
+			}
+			for _, name := range names {
+				marker := "  "
+				if name == c.cfg.Profile {
+					marker = "* "
+				}
+				fmt.Println(marker + name)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "/use ") {
+			name := strings.TrimSpace(strings.TrimPrefix(text, "/use "))
+			if name == "" {
+				fmt.Println("사용법: /use <프로필 이름>")
+				continue
+			}
+			if err := c.SwitchProfile(name); err != nil {
+				fmt.Println("error>", err)
+				continue
+			}
+			fmt.Printf("프로필을 %q(으)로 전환했습니다.\n", name)
+			continue
+		}
+		if text == "/policy" {
+			fmt.Println("현재 /policy:", c.HistoryPolicyName())
+			continue
+		}
+		if strings.HasPrefix(text, "/policy ") {
+			name := strings.TrimSpace(strings.TrimPrefix(text, "/policy "))
+			if !c.SetHistoryPolicyName(name) {
+				fmt.Println("사용법: /policy window|summarize")
+				continue
+			}
+			fmt.Println("/policy 를", name, "로 설정했습니다.")
+			continue
+		}
+		if text == "/tokens" {
+			// Pure read: estimate with WindowPolicy regardless of the active
+			// policy, so /tokens never triggers SummarizePolicy's network
+			// call to summarize just to report a count.
+			window := windowWithinBudget(sess.Messages, c.historyBudget())
+			fmt.Printf("tokens> 세션 전체 %d, 전송 예정(추정) %d / 예산 %d (MaxTokens %d, policy %s)\n",
+				totalTokens(sess.Messages), totalTokens(window), c.historyBudget(), c.cfg.MaxTokens, c.HistoryPolicyName())
+			continue
+		}
+		if text == "/redact" {
+			fmt.Println("현재 /redact 모드:", c.RedactMode())
+			continue
+		}
+		if strings.HasPrefix(text, "/redact ") {
+			arg := strings.TrimSpace(strings.TrimPrefix(text, "/redact "))
+			mode, ok := secrets.ParseMode(arg)
+			if !ok {
+				fmt.Println("사용법: /redact on|off|warn")
+				continue
+			}
+			c.SetRedactMode(mode)
+			fmt.Println("/redact 모드를", mode, "로 설정했습니다.")
 			continue
 		}
-		history = append(history, Message{Role: "user", Content: text})
-		history = trimHistory(history, c.cfg.MaxHistory)
+		// Redact before persisting, not just before sending, so a secret
+		// never ends up sitting in the on-disk session log either.
+		if c.RedactMode() != secrets.ModeOff {
+			if res := secrets.Scan(text, c.RedactMode()); len(res.Matches) > 0 {
+				fmt.Println(secrets.Summary(res.Matches))
+				if res.Blocked {
+					text = res.Text
+				}
+			}
+		}
+		if err := appendSessionMessage(dir, sess, Message{Role: "user", Content: text}); err != nil {
+			fmt.Println("세션 기록 실패:", err)
+		}
+		window, perr := c.ApplyHistoryPolicy(ctx, sess.Messages)
+		if perr != nil {
+			fmt.Println("히스토리 요약 실패, 최근 메시지만 사용합니다:", perr)
+			window = trimHistory(sess.Messages, c.cfg.MaxHistory)
+		}
 
-		ans, err := c.Chat(ctx, history)
+		genCtx, cancelGen := context.WithCancel(ctx)
+		chunks, err := c.ChatStream(genCtx, window)
 		if err != nil {
-			errMsg := err.Error()
-			lower := strings.ToLower(errMsg)
-			if strings.Contains(lower, "insufficient balance") || strings.Contains(lower, "no resource package") {
-				fmt.Println("error> 크레딧/리소스 패키지가 부족합니다.")
-				fmt.Println("hint> ZAI 콘솔에서 잔액/패키지를 충전한 뒤 다시 시도하세요.")
-				fmt.Println("hint> 키/엔드포인트 점검: ZAI_API_KEY, LZAI_BASE_URL, LZAI_API_PREFIX")
-			} else {
-				fmt.Println("error>", errMsg)
-			}
+			cancelGen()
+			printChatError(err)
 			continue
 		}
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT)
+		go func() {
+			if _, ok := <-sigCh; ok {
+				cancelGen()
+			}
+		}()
 		fmt.Println("ai>")
 		w, h := detectTTYSize(c.cfg.ScreenWidth, c.cfg.ScreenHeight)
-		pagePrint(ans, w, h, s)
-		history = append(history, Message{Role: "assistant", Content: ans})
-		history = trimHistory(history, c.cfg.MaxHistory)
+		ans, streamErr := streamPrint(cancelGen, chunks, w, h, s)
+		signal.Stop(sigCh)
+		close(sigCh)
+		cancelGen()
+		if streamErr != nil {
+			printChatError(streamErr)
+			continue
+		}
+		if err := appendSessionMessage(dir, sess, Message{Role: "assistant", Content: ans}); err != nil {
+			fmt.Println("세션 기록 실패:", err)
+		}
 	}
 	return s.Err()
 }
@@ -0,0 +1,180 @@
+package lightzai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EstimateTokens roughly estimates how many tokens s costs: ~4 chars/token
+// for ASCII, ~1.5 chars/token for the CJK/wide-rune ranges runeDisplayWidth
+// already recognizes (those tend to be denser per-token in practice).
+func EstimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	var asciiChars, wideChars int
+	for _, r := range s {
+		if runeDisplayWidth(r) == 2 {
+			wideChars++
+		} else {
+			asciiChars++
+		}
+	}
+	tokens := math.Ceil(float64(asciiChars)/4.0 + float64(wideChars)/1.5)
+	if tokens < 1 {
+		tokens = 1
+	}
+	return int(tokens)
+}
+
+func totalTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += EstimateTokens(m.Content)
+	}
+	return total
+}
+
+// HistoryPolicy decides what subset of a session's full message history is
+// actually sent to Chat/ChatStream, under a token budget.
+type HistoryPolicy interface {
+	Apply(ctx context.Context, c *Client, messages []Message, budget int) ([]Message, error)
+}
+
+// WindowPolicy keeps the system prompt plus as many of the most recent
+// messages as fit under budget, dropping older ones outright.
+type WindowPolicy struct{}
+
+func (WindowPolicy) Apply(_ context.Context, _ *Client, messages []Message, budget int) ([]Message, error) {
+	return windowWithinBudget(messages, budget), nil
+}
+
+// summaryPrefix marks an assistant message as a SummarizePolicy-produced
+// stand-in for folded-away turns, rather than a real reply.
+const summaryPrefix = "[요약] "
+
+func isSummaryMessage(m Message) bool {
+	return m.Role == "assistant" && strings.HasPrefix(m.Content, summaryPrefix)
+}
+
+// windowWithinBudget keeps the system prompt and any summary messages
+// pinned (they already stand in for history that's been folded away) and
+// fills the remaining budget with as many of the most recent other
+// messages as fit, dropping older ones outright.
+func windowWithinBudget(messages []Message, budget int) []Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	sys := messages[0]
+	used := EstimateTokens(sys.Content)
+
+	var pinned, rest []Message
+	for _, m := range messages[1:] {
+		if isSummaryMessage(m) {
+			pinned = append(pinned, m)
+			used += EstimateTokens(m.Content)
+			continue
+		}
+		rest = append(rest, m)
+	}
+
+	var kept []Message
+	for i := len(rest) - 1; i >= 0; i-- {
+		t := EstimateTokens(rest[i].Content)
+		if used+t > budget && len(kept) > 0 {
+			break
+		}
+		kept = append(kept, rest[i])
+		used += t
+	}
+	for l, r := 0, len(kept)-1; l < r; l, r = l+1, r-1 {
+		kept[l], kept[r] = kept[r], kept[l]
+	}
+	out := make([]Message, 0, 1+len(pinned)+len(kept))
+	out = append(out, sys)
+	out = append(out, pinned...)
+	out = append(out, kept...)
+	return out
+}
+
+// SummarizePolicy folds the oldest DropPairs user/assistant turns into a
+// single synthetic "[요약] ..." assistant message (produced by asking
+// Client.Chat to summarize just the dropped turns) whenever the history
+// would otherwise exceed budget, instead of silently discarding them.
+type SummarizePolicy struct {
+	// DropPairs is how many oldest turns are folded together per round.
+	// Defaults to 2 when <= 0.
+	DropPairs int
+}
+
+const maxSummarizeRounds = 25
+
+func (p SummarizePolicy) Apply(ctx context.Context, c *Client, messages []Message, budget int) ([]Message, error) {
+	dropPairs := p.DropPairs
+	if dropPairs <= 0 {
+		dropPairs = 2
+	}
+
+	working := messages
+	for round := 0; totalTokens(working) > budget && len(working) > 1+dropPairs*2 && round < maxSummarizeRounds; round++ {
+		summarized, err := summarizeOldest(ctx, c, working, dropPairs, budget)
+		if err != nil {
+			return windowWithinBudget(working, budget), err
+		}
+		working = summarized
+	}
+	if totalTokens(working) > budget {
+		working = windowWithinBudget(working, budget)
+	}
+	return working, nil
+}
+
+// summarizeOldest replaces the oldest dropPairs*2 messages right after the
+// system prompt with one assistant summary message.
+func summarizeOldest(ctx context.Context, c *Client, messages []Message, dropPairs, budget int) ([]Message, error) {
+	if len(messages) < 2 {
+		return messages, nil
+	}
+	sys := messages[0]
+	rest := messages[1:]
+	dropCount := dropPairs * 2
+	if dropCount > len(rest) {
+		dropCount = len(rest)
+	}
+	dropped := rest[:dropCount]
+	kept := rest[dropCount:]
+
+	summary, err := summarizeTurns(ctx, c, dropped, budget)
+	if err != nil {
+		return messages, err
+	}
+
+	out := make([]Message, 0, 2+len(kept))
+	out = append(out, sys, Message{Role: "assistant", Content: summaryPrefix + summary})
+	out = append(out, kept...)
+	return out, nil
+}
+
+// summarizeTurns asks Client.Chat to summarize turns in one or two
+// sentences. The request itself is trimmed to budget, so the summarization
+// call never blows past the same token ceiling it's meant to protect.
+func summarizeTurns(ctx context.Context, c *Client, turns []Message, budget int) (string, error) {
+	var b strings.Builder
+	for _, m := range turns {
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Content)
+	}
+	req := windowWithinBudget([]Message{
+		{Role: "system", Content: "다음은 대화 기록 중 오래된 부분입니다. 한두 문장으로 간결하게 요약하세요."},
+		{Role: "user", Content: b.String()},
+	}, budget)
+	return c.Chat(ctx, req)
+}
+
+func policyFromName(name string) HistoryPolicy {
+	if name == "summarize" {
+		return SummarizePolicy{}
+	}
+	return WindowPolicy{}
+}
@@ -0,0 +1,89 @@
+package lightzai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		min  int
+	}{
+		{"empty", "", 0},
+		{"ascii", "hello world", 1},
+		{"wide", "안녕하세요", 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EstimateTokens(tc.in); got < tc.min {
+				t.Fatalf("EstimateTokens(%q) = %d, want >= %d", tc.in, got, tc.min)
+			}
+		})
+	}
+}
+
+func TestWindowWithinBudgetKeepsSystemAndRecent(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: strings.Repeat("a", 40)},
+		{Role: "assistant", Content: strings.Repeat("b", 40)},
+		{Role: "user", Content: "recent"},
+	}
+	kept := windowWithinBudget(messages, 5)
+	if kept[0].Role != "system" {
+		t.Fatalf("expected system prompt to always be kept, got %+v", kept[0])
+	}
+	if kept[len(kept)-1].Content != "recent" {
+		t.Fatalf("expected most recent message to be kept, got %+v", kept[len(kept)-1])
+	}
+}
+
+func TestSummarizePolicyApplyFoldsOldestTurns(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(chatResponse{
+			Choices: []struct {
+				Message Message `json:"message"`
+			}{{Message: Message{Role: "assistant", Content: "요약됨"}}},
+		})
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{
+		APIKey:    "test",
+		Scheme:    "http",
+		BaseURL:   srv.Listener.Addr().String(),
+		APIPrefix: "",
+		MaxTokens: 100,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	messages := []Message{{Role: "system", Content: "sys"}}
+	for i := 0; i < 6; i++ {
+		messages = append(messages, Message{Role: "user", Content: strings.Repeat("x", 60)})
+	}
+
+	out, err := (SummarizePolicy{}).Apply(context.Background(), c, messages, 20)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(out) >= len(messages) {
+		t.Fatalf("expected summarization to shrink history, got %d messages from %d", len(out), len(messages))
+	}
+	found := false
+	for _, m := range out {
+		if strings.Contains(m.Content, "요약") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a summary message in output, got %+v", out)
+	}
+}
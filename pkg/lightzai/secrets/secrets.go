@@ -0,0 +1,190 @@
+// Package secrets scans outgoing chat messages for API keys, tokens, and
+// other high-signal secrets before they leave the machine.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mode controls what Scan does with a match.
+type Mode int
+
+const (
+	ModeOff Mode = iota
+	ModeWarn
+	ModeBlock
+)
+
+// ParseMode parses the /redact command argument / LZAI_REDACT_MODE value.
+// "on" and "block" both select ModeBlock.
+func ParseMode(s string) (Mode, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "off":
+		return ModeOff, true
+	case "warn":
+		return ModeWarn, true
+	case "on", "block":
+		return ModeBlock, true
+	default:
+		return ModeOff, false
+	}
+}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeWarn:
+		return "warn"
+	case ModeBlock:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// Match is one detected secret.
+type Match struct {
+	Kind string
+	Text string
+}
+
+// Result is the outcome of scanning a single message.
+type Result struct {
+	Text    string // message after redaction; unchanged unless Blocked
+	Matches []Match
+	Blocked bool
+}
+
+type namedPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+var highSignal = []namedPattern{
+	{"aws-key", regexp.MustCompile(`AKIA[0-9A-Z]{16}`)},
+	{"github-token", regexp.MustCompile(`ghp_[A-Za-z0-9]{36}`)},
+	{"api-key", regexp.MustCompile(`sk-[A-Za-z0-9]{20,}`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"bearer-token", regexp.MustCompile(`Bearer [A-Za-z0-9\-_.=]+`)},
+}
+
+var (
+	hexToken    = regexp.MustCompile(`\b[0-9a-fA-F]{20,}\b`)
+	base64Token = regexp.MustCompile(`\b[A-Za-z0-9+/=]{20,}\b`)
+)
+
+const (
+	hexEntropyThreshold    = 3.0
+	base64EntropyThreshold = 4.0
+)
+
+// Scan looks for the high-signal regexes plus, for anything they miss, a
+// Shannon-entropy fallback over base64/hex-looking runs of length >= 20. In
+// ModeOff nothing is scanned; in ModeWarn matches are reported but text
+// passes through unchanged; in ModeBlock every match is replaced with
+// «REDACTED:kind».
+func Scan(text string, mode Mode) Result {
+	if mode == ModeOff {
+		return Result{Text: text}
+	}
+
+	type span struct {
+		start, end int
+		kind       string
+	}
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, sp := range spans {
+			if start < sp.end && end > sp.start {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range highSignal {
+		for _, loc := range p.re.FindAllStringIndex(text, -1) {
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{loc[0], loc[1], p.kind})
+		}
+	}
+	for _, loc := range hexToken.FindAllStringIndex(text, -1) {
+		if overlaps(loc[0], loc[1]) {
+			continue
+		}
+		if shannonEntropy(text[loc[0]:loc[1]]) > hexEntropyThreshold {
+			spans = append(spans, span{loc[0], loc[1], "high-entropy"})
+		}
+	}
+	for _, loc := range base64Token.FindAllStringIndex(text, -1) {
+		if overlaps(loc[0], loc[1]) {
+			continue
+		}
+		if shannonEntropy(text[loc[0]:loc[1]]) > base64EntropyThreshold {
+			spans = append(spans, span{loc[0], loc[1], "high-entropy"})
+		}
+	}
+
+	if len(spans) == 0 {
+		return Result{Text: text}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	matches := make([]Match, 0, len(spans))
+	for _, sp := range spans {
+		matches = append(matches, Match{Kind: sp.kind, Text: text[sp.start:sp.end]})
+	}
+	if mode == ModeWarn {
+		return Result{Text: text, Matches: matches}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		if sp.start < last {
+			// Defensive: spans are built to be non-overlapping, but don't
+			// let a future pattern addition panic if that ever slips.
+			continue
+		}
+		b.WriteString(text[last:sp.start])
+		b.WriteString(fmt.Sprintf("«REDACTED:%s»", sp.kind))
+		last = sp.end
+	}
+	b.WriteString(text[last:])
+	return Result{Text: b.String(), Matches: matches, Blocked: true}
+}
+
+// shannonEntropy computes H = -Σ p_i log2 p_i over s's character frequencies.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	freq := make(map[rune]int)
+	for _, r := range s {
+		freq[r]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, count := range freq {
+		p := float64(count) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Summary renders the "redacted> N secret(s) (kind1, kind2)" line shown
+// before a scrubbed request goes out, or "" if there's nothing to report.
+func Summary(matches []Match) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	kinds := make([]string, 0, len(matches))
+	for _, m := range matches {
+		kinds = append(kinds, m.Kind)
+	}
+	return fmt.Sprintf("redacted> %d secret(s) (%s)", len(matches), strings.Join(kinds, ", "))
+}
@@ -0,0 +1,291 @@
+package lightzai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SessionHeader is the small metadata file saved alongside a session's
+// append-only message log.
+type SessionHeader struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	Model         string    `json:"model"`
+	Profile       string    `json:"profile"`
+	TokenEstimate int       `json:"tokenEstimate"`
+}
+
+func (h SessionHeader) displayName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.ID
+}
+
+// Session is a conversation persisted under
+// ~/.local/share/light-zai/sessions/<id>.jsonl. Messages holds the
+// complete history; RunREPL sends only the client's HistoryPolicy-trimmed
+// window of it to Chat/ChatStream.
+type Session struct {
+	SessionHeader
+	Messages []Message
+}
+
+func sessionsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", fmt.Errorf("home directory를 찾을 수 없습니다")
+	}
+	dir := filepath.Join(home, ".local", "share", "light-zai", "sessions")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func sessionHeaderPath(dir, id string) string { return filepath.Join(dir, id+".header.json") }
+func sessionLogPath(dir, id string) string    { return filepath.Join(dir, id+".jsonl") }
+func sessionLockPath(dir, id string) string   { return filepath.Join(dir, id+".lock") }
+
+func newSessionID() string {
+	return time.Now().UTC().Format("20060102T150405.000000000")
+}
+
+func newSessionFor(c *Client) *Session {
+	now := time.Now()
+	return &Session{
+		SessionHeader: SessionHeader{
+			ID:        newSessionID(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Model:     c.cfg.Model,
+			Profile:   c.cfg.Profile,
+		},
+	}
+}
+
+// sessionLock is a per-session flock guarding the header/log pair so two
+// concurrent REPLs can't corrupt the same session.
+type sessionLock struct {
+	f *os.File
+}
+
+func lockSession(dir, id string) (*sessionLock, error) {
+	f, err := os.OpenFile(sessionLockPath(dir, id), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("세션이 다른 프로세스에서 사용 중입니다")
+	}
+	return &sessionLock{f: f}, nil
+}
+
+func (l *sessionLock) unlock() {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	l.f.Close()
+}
+
+func saveSessionHeader(dir string, h SessionHeader) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sessionHeaderPath(dir, h.ID), b, 0o600)
+}
+
+func loadSessionHeader(dir, id string) (*SessionHeader, error) {
+	b, err := os.ReadFile(sessionHeaderPath(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	var h SessionHeader
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func loadSessionMessages(dir, id string) ([]Message, error) {
+	b, err := os.ReadFile(sessionLogPath(dir, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var msgs []Message
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// appendSessionMessage appends m to the session's on-disk log, updates
+// Messages/UpdatedAt/TokenEstimate in memory, and rewrites the header.
+func appendSessionMessage(dir string, sess *Session, m Message) error {
+	f, err := os.OpenFile(sessionLogPath(dir, sess.ID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	sess.Messages = append(sess.Messages, m)
+	sess.UpdatedAt = time.Now()
+	sess.TokenEstimate += EstimateTokens(m.Content)
+	return saveSessionHeader(dir, sess.SessionHeader)
+}
+
+// ensureSystemPrompt seeds an empty session (new, cleared, or branched down
+// to nothing) with the default system message.
+func ensureSystemPrompt(dir string, sess *Session) error {
+	if len(sess.Messages) > 0 {
+		return nil
+	}
+	return appendSessionMessage(dir, sess, Message{Role: "system", Content: "당신은 간결하고 정확한 코딩 도우미입니다."})
+}
+
+func listSessionHeaders(dir string) ([]SessionHeader, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var headers []SessionHeader
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".header.json") {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), ".header.json")
+		h, err := loadSessionHeader(dir, id)
+		if err != nil {
+			continue
+		}
+		headers = append(headers, *h)
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].UpdatedAt.After(headers[j].UpdatedAt) })
+	return headers, nil
+}
+
+func mostRecentSessionHeader(dir string) (*SessionHeader, error) {
+	headers, err := listSessionHeaders(dir)
+	if err != nil || len(headers) == 0 {
+		return nil, err
+	}
+	return &headers[0], nil
+}
+
+// findSessionHeader resolves a /load or /branch argument: an exact ID
+// match wins, otherwise the most recently updated session with that name.
+func findSessionHeader(dir, nameOrID string) (*SessionHeader, error) {
+	headers, err := listSessionHeaders(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		if h.ID == nameOrID {
+			hh := h
+			return &hh, nil
+		}
+	}
+	for _, h := range headers {
+		if h.Name == nameOrID {
+			hh := h
+			return &hh, nil
+		}
+	}
+	return nil, fmt.Errorf("세션 %q을(를) 찾을 수 없습니다", nameOrID)
+}
+
+// branchSession forks src into a new session, keeping every message except
+// the last n (n == 0 forks from the current end of the conversation).
+func branchSession(dir string, src *Session, n int) (*Session, error) {
+	keep := len(src.Messages) - n
+	if keep < 0 {
+		keep = 0
+	}
+	msgs := make([]Message, keep)
+	copy(msgs, src.Messages[:keep])
+
+	now := time.Now()
+	branch := &Session{
+		SessionHeader: SessionHeader{
+			ID:        newSessionID(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Model:     src.Model,
+			Profile:   src.Profile,
+		},
+	}
+
+	f, err := os.OpenFile(sessionLogPath(dir, branch.ID), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return nil, err
+		}
+		branch.TokenEstimate += EstimateTokens(m.Content)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	branch.Messages = msgs
+	if err := saveSessionHeader(dir, branch.SessionHeader); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+// exportMarkdown renders sess's messages (skipping the system prompt) as a
+// Markdown transcript.
+func exportMarkdown(sess *Session, path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", sess.displayName())
+	for _, m := range sess.Messages {
+		switch m.Role {
+		case "system":
+			continue
+		case "user":
+			fmt.Fprintf(&b, "## You\n\n%s\n\n", m.Content)
+		case "assistant":
+			fmt.Fprintf(&b, "## AI\n\n%s\n\n", m.Content)
+		default:
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", m.Role, m.Content)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}